@@ -0,0 +1,84 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package api4
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/audit"
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+func (api *API) InitPlugin() {
+	api.BaseRoutes.Plugins.Handle("/install_from_channel", api.ApiSessionRequired(installPluginFromChannel)).Methods("POST")
+	api.BaseRoutes.Plugins.Handle("/search", api.ApiSessionRequired(searchPluginChannels)).Methods("GET")
+}
+
+func installPluginFromChannel(c *Context, w http.ResponseWriter, r *http.Request) {
+	if !c.App.SessionHasPermissionTo(*c.App.Session(), model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		c.SetInvalidParam("name")
+		return
+	}
+	version := r.URL.Query().Get("version")
+
+	auditRec := c.MakeAuditRecord("installPluginFromChannel", audit.Fail)
+	defer c.LogAuditRec(auditRec)
+	auditRec.AddMeta("name", name)
+	auditRec.AddMeta("version", version)
+
+	entry, appErr := c.App.ResolvePluginChannelEntry(name, version, nil)
+	if appErr != nil {
+		c.Err = appErr
+		return
+	}
+
+	manifest, appErr := c.App.InstallPluginFromChannelEntry(entry, true)
+	if appErr != nil {
+		c.Err = appErr
+		return
+	}
+
+	auditRec.Success()
+	auditRec.AddMeta("plugin_id", manifest.Id)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(manifest.ToJson())
+}
+
+func searchPluginChannels(c *Context, w http.ResponseWriter, r *http.Request) {
+	if !c.App.SessionHasPermissionTo(*c.App.Session(), model.PERMISSION_MANAGE_SYSTEM) {
+		c.SetPermissionError(model.PERMISSION_MANAGE_SYSTEM)
+		return
+	}
+
+	query := r.URL.Query().Get("query")
+
+	entries := c.App.FetchPluginChannelIndexes(nil)
+	matches := make([]*model.PluginChannelIndexEntry, 0, len(entries))
+	for _, entry := range entries {
+		if query == "" || containsIgnoreCase(entry.Name, query) {
+			matches = append(matches, entry)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	b, err := json.Marshal(matches)
+	if err != nil {
+		c.Err = model.NewAppError("searchPluginChannels", "api.marshal_error", nil, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(b)
+}
+
+func containsIgnoreCase(name, query string) bool {
+	return strings.Contains(strings.ToLower(name), strings.ToLower(query))
+}
@@ -0,0 +1,246 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+
+	"github.com/mattermost/mattermost-server/v5/mlog"
+	"github.com/mattermost/mattermost-server/v5/model"
+	"github.com/mattermost/mattermost-server/v5/plugin"
+)
+
+// BootstrapPlugins scans dir for .tar.gz plugin bundles, validates each
+// against the installed public keys, and installs any that are missing or
+// older than the bundle's version. Plugin ids in requiredIds are recorded in
+// PluginSettings.RequiredPluginIds, which EnforceRequiredPlugins and
+// EnforceRequiredPluginsAfterSync check. SyncPluginsActiveState does not call
+// either of them, so a required plugin that fails to activate does not by
+// itself stop the server from starting.
+func (a *App) BootstrapPlugins(dir string, requiredIds []string) ([]*model.Manifest, *model.AppError) {
+	bundles, err := filepath.Glob(filepath.Join(dir, "*.tar.gz"))
+	if err != nil {
+		return nil, model.NewAppError("BootstrapPlugins", "app.plugin.bootstrap_scan_failed.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	existing, appErr := a.GetPlugins()
+	if appErr != nil {
+		return nil, appErr
+	}
+	installedVersion := make(map[string]string)
+	for _, p := range append(append([]*model.PluginInfo{}, existing.Active...), existing.Inactive...) {
+		installedVersion[p.Manifest.Id] = p.Manifest.Version
+	}
+
+	var installed []*model.Manifest
+	for _, bundlePath := range bundles {
+		manifest, err := manifestFromBundle(bundlePath)
+		if err != nil {
+			mlog.Error("plugin bootstrap: failed to read manifest", mlog.String("path", bundlePath), mlog.Err(err))
+			continue
+		}
+
+		if version, ok := installedVersion[manifest.Id]; ok && !isNewerVersion(manifest.Version, version) {
+			continue
+		}
+
+		if appErr := a.installPluginBundleVerified(bundlePath); appErr != nil {
+			mlog.Error("plugin bootstrap: failed to install bundle", mlog.String("path", bundlePath), mlog.Err(appErr))
+			continue
+		}
+
+		installed = append(installed, manifest)
+	}
+
+	if len(requiredIds) > 0 {
+		config := a.Config().Clone()
+		config.PluginSettings.RequiredPluginIds = requiredIds
+		if _, _, err := a.SaveConfig(config, true); err != nil {
+			return installed, model.NewAppError("BootstrapPlugins", "app.plugin.bootstrap_required_failed.app_error", nil, err.Error(), http.StatusInternalServerError)
+		}
+	}
+
+	return installed, nil
+}
+
+// EnforceRequiredPlugins returns an error naming any plugin in
+// PluginSettings.RequiredPluginIds that isn't active.
+// EnforceRequiredPluginsAfterSync wraps this with the fatal-on-startup
+// behavior described there.
+func (a *App) EnforceRequiredPlugins() *model.AppError {
+	pluginsResp, appErr := a.GetPlugins()
+	if appErr != nil {
+		return appErr
+	}
+
+	active := make(map[string]bool)
+	for _, p := range pluginsResp.Active {
+		active[p.Manifest.Id] = true
+	}
+
+	var missing []string
+	for _, id := range a.Config().PluginSettings.RequiredPluginIds {
+		if !active[id] {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		return model.NewAppError("EnforceRequiredPlugins", "app.plugin.required_plugin_inactive.app_error", map[string]interface{}{"PluginIds": missing}, "", http.StatusInternalServerError)
+	}
+
+	return nil
+}
+
+// DumpPluginMetadata parses the manifest of every .tar.gz bundle in dir and
+// returns it without installing anything or touching the DB. It's intended
+// for CI and image-build pipelines that need to inspect bundles offline.
+func (a *App) DumpPluginMetadata(dir string) ([]*model.Manifest, *model.AppError) {
+	bundles, err := filepath.Glob(filepath.Join(dir, "*.tar.gz"))
+	if err != nil {
+		return nil, model.NewAppError("DumpPluginMetadata", "app.plugin.bootstrap_scan_failed.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	var manifests []*model.Manifest
+	for _, bundlePath := range bundles {
+		manifest, err := manifestFromBundle(bundlePath)
+		if err != nil {
+			mlog.Error("plugin metadata: failed to read manifest", mlog.String("path", bundlePath), mlog.Err(err))
+			continue
+		}
+		manifests = append(manifests, manifest)
+	}
+
+	return manifests, nil
+}
+
+// installPluginBundleVerified verifies bundlePath against the installed
+// public keys using the detached signature in bundlePath+".sig", then
+// installs it. A bundle with no .sig file alongside it is not installed.
+func (a *App) installPluginBundleVerified(bundlePath string) *model.AppError {
+	signature, err := ioutil.ReadFile(bundlePath + ".sig")
+	if err != nil {
+		return model.NewAppError("installPluginBundleVerified", "app.plugin.bootstrap_install_failed.app_error", nil, "missing signature: "+err.Error(), http.StatusBadRequest)
+	}
+
+	sigFile, osErr := os.Open(bundlePath)
+	if osErr != nil {
+		return model.NewAppError("installPluginBundleVerified", "app.plugin.bootstrap_install_failed.app_error", nil, osErr.Error(), http.StatusInternalServerError)
+	}
+	appErr := a.verifyPluginChannelSignature(sigFile, string(signature))
+	sigFile.Close()
+	if appErr != nil {
+		return appErr
+	}
+
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return model.NewAppError("installPluginBundleVerified", "app.plugin.bootstrap_install_failed.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+	defer f.Close()
+
+	_, appErr := a.InstallPlugin(f, true)
+	return appErr
+}
+
+// manifestFromBundle extracts bundlePath to a temp directory and parses its
+// plugin.json/plugin.yaml manifest.
+func manifestFromBundle(bundlePath string) (*model.Manifest, error) {
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open bundle")
+	}
+	defer f.Close()
+
+	tmpDir, err := ioutil.TempDir("", "plugin-metadata-")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create temp dir")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := extractTarGz(f, tmpDir); err != nil {
+		return nil, errors.Wrap(err, "failed to extract bundle")
+	}
+
+	manifest, _, err := plugin.FindManifest(tmpDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find manifest")
+	}
+
+	return manifest, nil
+}
+
+// isNewerVersion reports whether candidate is a newer semver than current.
+// An unparseable candidate is treated as not newer; a parseable candidate is
+// treated as newer than an unparseable current version.
+func isNewerVersion(candidate, current string) bool {
+	c, err := semverOrZero(candidate)
+	if err != nil {
+		return false
+	}
+	cur, err := semverOrZero(current)
+	if err != nil {
+		return true
+	}
+	return c.GT(cur)
+}
+
+func semverOrZero(version string) (semver.Version, error) {
+	return semver.Parse(version)
+}
+
+// extractTarGz extracts a gzip-compressed tarball into destDir.
+func extractTarGz(r io.Reader, destDir string) error {
+	gzr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gzr.Close()
+
+	tr := tar.NewReader(gzr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(destDir, header.Name)
+		if target != destDir && !strings.HasPrefix(target, destDir+string(os.PathSeparator)) {
+			return errors.Errorf("tar entry %q escapes destination directory", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode))
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				out.Close()
+				return err
+			}
+			out.Close()
+		}
+	}
+}
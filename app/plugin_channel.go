@@ -0,0 +1,311 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/openpgp"
+
+	"github.com/mattermost/mattermost-server/v5/mlog"
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// maxPluginChannelIndexBytes caps how much of a channel's index response we
+// read, so a misbehaving or malicious channel can't exhaust memory.
+const maxPluginChannelIndexBytes = 10 * 1024 * 1024
+
+// pluginSignatureDir, relative to PluginSettings.Directory, holds the
+// original signed tarball and detached signature for every plugin installed
+// from a channel, so that `plugin doctor` can later re-verify the signature
+// against whatever keys are currently installed.
+const pluginSignatureDir = ".signatures"
+
+var pluginChannelHTTPClient = &http.Client{Timeout: 15 * time.Second}
+
+// fetchAllPluginChannelEntries concurrently fetches the index document from
+// every configured plugin channel, writing human-readable progress to
+// progressWriter as each fetch completes. Failures fetching an individual
+// channel are logged and otherwise ignored so that one unreachable channel
+// doesn't prevent installing from the rest. The returned entries preserve
+// config precedence order: channel i's entries all precede channel i+1's,
+// regardless of which channel's HTTP request finished first.
+func (a *App) fetchAllPluginChannelEntries(progressWriter io.Writer) []*model.PluginChannelIndexEntry {
+	channels := a.Config().PluginSettings.Channels
+
+	var wg sync.WaitGroup
+	var progressMu sync.Mutex
+	perChannel := make([][]*model.PluginChannelIndexEntry, len(channels))
+
+	for i, channel := range channels {
+		wg.Add(1)
+		go func(i int, channel *model.PluginChannel) {
+			defer wg.Done()
+
+			index, err := fetchPluginChannelIndex(channel.Url)
+			if err != nil {
+				mlog.Error("failed to fetch plugin channel", mlog.String("channel", channel.Name), mlog.Err(err))
+				if progressWriter != nil {
+					progressMu.Lock()
+					io.WriteString(progressWriter, "failed to fetch channel "+channel.Name+": "+err.Error()+"\n")
+					progressMu.Unlock()
+				}
+				return
+			}
+
+			if progressWriter != nil {
+				progressMu.Lock()
+				io.WriteString(progressWriter, "fetched channel "+channel.Name+"\n")
+				progressMu.Unlock()
+			}
+
+			perChannel[i] = index.Plugins
+		}(i, channel)
+	}
+	wg.Wait()
+
+	var entries []*model.PluginChannelIndexEntry
+	for _, channelEntries := range perChannel {
+		entries = append(entries, channelEntries...)
+	}
+
+	return entries
+}
+
+// FetchPluginChannelIndexes returns the merged, deduplicated-by-highest-
+// version view of every configured channel's offerings, suitable for
+// listing or searching.
+func (a *App) FetchPluginChannelIndexes(progressWriter io.Writer) []*model.PluginChannelIndexEntry {
+	return mergePluginChannelEntries(a.fetchAllPluginChannelEntries(progressWriter))
+}
+
+func fetchPluginChannelIndex(rawURL string) (*model.PluginChannelIndex, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid channel url")
+	}
+	if parsed.Scheme != "https" {
+		return nil, errors.New("channel url must use https")
+	}
+
+	resp, err := pluginChannelHTTPClient.Get(rawURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to reach channel")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("channel returned status %d", resp.StatusCode)
+	}
+
+	var index model.PluginChannelIndex
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxPluginChannelIndexBytes)).Decode(&index); err != nil {
+		return nil, errors.Wrap(err, "failed to parse channel index")
+	}
+
+	return &index, nil
+}
+
+// mergePluginChannelEntries deduplicates entries by name, keeping only the
+// entry with the highest semver version for each name. Entries are walked in
+// config precedence order, so on a version tie the entry from the
+// higher-precedence (earlier) channel wins.
+func mergePluginChannelEntries(entries []*model.PluginChannelIndexEntry) []*model.PluginChannelIndexEntry {
+	best := make(map[string]*model.PluginChannelIndexEntry)
+	bestVersion := make(map[string]semver.Version)
+
+	for _, entry := range entries {
+		version, err := semver.Parse(entry.Version)
+		if err != nil {
+			continue
+		}
+
+		if existing, ok := bestVersion[entry.Name]; !ok || version.GT(existing) {
+			best[entry.Name] = entry
+			bestVersion[entry.Name] = version
+		}
+	}
+
+	merged := make([]*model.PluginChannelIndexEntry, 0, len(best))
+	for _, entry := range best {
+		merged = append(merged, entry)
+	}
+
+	return merged
+}
+
+// ResolvePluginChannelEntry finds the entry for the given plugin name across
+// all configured channels, optionally pinned to a specific version. When
+// version is pinned, the unmerged entry set is searched directly so that an
+// older, still-offered version isn't hidden by merge-by-highest-version.
+// When version is empty, the highest available version is returned.
+func (a *App) ResolvePluginChannelEntry(name, version string, progressWriter io.Writer) (*model.PluginChannelIndexEntry, *model.AppError) {
+	entries := a.fetchAllPluginChannelEntries(progressWriter)
+
+	if version == "" {
+		for _, entry := range mergePluginChannelEntries(entries) {
+			if entry.Name == name {
+				return entry, nil
+			}
+		}
+	} else {
+		for _, entry := range entries {
+			if entry.Name == name && entry.Version == version {
+				return entry, nil
+			}
+		}
+	}
+
+	return nil, model.NewAppError("ResolvePluginChannelEntry", "app.plugin.channel_entry_not_found.app_error", nil, "name="+name, http.StatusNotFound)
+}
+
+// InstallPluginFromChannelEntry downloads the tarball referenced by entry,
+// verifies its SHA-256 digest and detached GPG signature against the
+// already-installed public keys, and installs it via InstallPlugin.
+func (a *App) InstallPluginFromChannelEntry(entry *model.PluginChannelIndexEntry, replace bool) (*model.Manifest, *model.AppError) {
+	parsed, err := url.Parse(entry.Url)
+	if err != nil {
+		return nil, model.NewAppError("InstallPluginFromChannelEntry", "app.plugin.channel_download_failed.app_error", nil, err.Error(), http.StatusBadRequest)
+	}
+	if parsed.Scheme != "https" {
+		return nil, model.NewAppError("InstallPluginFromChannelEntry", "app.plugin.channel_download_failed.app_error", nil, "artifact url must use https", http.StatusBadRequest)
+	}
+
+	resp, err := pluginChannelHTTPClient.Get(entry.Url)
+	if err != nil {
+		return nil, model.NewAppError("InstallPluginFromChannelEntry", "app.plugin.channel_download_failed.app_error", nil, err.Error(), http.StatusBadRequest)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, model.NewAppError("InstallPluginFromChannelEntry", "app.plugin.channel_download_failed.app_error", nil, fmt.Sprintf("artifact url returned status %d", resp.StatusCode), http.StatusBadGateway)
+	}
+
+	tmpFile, err := ioutil.TempFile("", "plugin-channel-*.tar.gz")
+	if err != nil {
+		return nil, model.NewAppError("InstallPluginFromChannelEntry", "app.plugin.channel_download_failed.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmpFile, hasher), resp.Body); err != nil {
+		return nil, model.NewAppError("InstallPluginFromChannelEntry", "app.plugin.channel_download_failed.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	if hex.EncodeToString(hasher.Sum(nil)) != entry.Sha256 {
+		return nil, model.NewAppError("InstallPluginFromChannelEntry", "app.plugin.channel_checksum_mismatch.app_error", nil, "name="+entry.Name, http.StatusBadRequest)
+	}
+
+	if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
+		return nil, model.NewAppError("InstallPluginFromChannelEntry", "app.plugin.channel_download_failed.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	if appErr := a.verifyPluginChannelSignature(tmpFile, entry.Signature); appErr != nil {
+		return nil, appErr
+	}
+
+	if _, err := tmpFile.Seek(0, io.SeekStart); err != nil {
+		return nil, model.NewAppError("InstallPluginFromChannelEntry", "app.plugin.channel_download_failed.app_error", nil, err.Error(), http.StatusInternalServerError)
+	}
+
+	manifest, appErr := a.InstallPlugin(tmpFile, replace)
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	if err := a.retainPluginArtifact(manifest.Id, tmpFile, entry.Signature); err != nil {
+		mlog.Warn("failed to retain signed plugin artifact for later verification", mlog.String("plugin_id", manifest.Id), mlog.Err(err))
+	}
+
+	return manifest, nil
+}
+
+// verifyPluginChannelSignature checks the detached, base64-encoded signature
+// against every public key previously installed via `plugin add-key`.
+func (a *App) verifyPluginChannelSignature(plugin io.ReadSeeker, signature string) *model.AppError {
+	keyNames, appErr := a.GetPluginPublicKeys()
+	if appErr != nil {
+		return appErr
+	}
+
+	for _, keyName := range keyNames {
+		key, err := a.GetPublicKey(keyName)
+		if err != nil {
+			continue
+		}
+
+		if err := verifyDetachedSignature(plugin, signature, key); err == nil {
+			return nil
+		}
+
+		if _, err := plugin.Seek(0, io.SeekStart); err != nil {
+			return model.NewAppError("verifyPluginChannelSignature", "app.plugin.channel_download_failed.app_error", nil, err.Error(), http.StatusInternalServerError)
+		}
+	}
+
+	return model.NewAppError("verifyPluginChannelSignature", "app.plugin.channel_signature_invalid.app_error", nil, "", http.StatusBadRequest)
+}
+
+// retainPluginArtifact copies the signed tarball and its detached signature
+// into pluginSignatureDir, keyed by plugin id, so a later `plugin doctor`
+// run can re-verify the signature without needing to re-download it.
+func (a *App) retainPluginArtifact(pluginId string, tarball io.ReadSeeker, signature string) error {
+	dir := filepath.Join(*a.Config().PluginSettings.Directory, pluginSignatureDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrap(err, "failed to create signature retention directory")
+	}
+
+	if _, err := tarball.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrap(err, "failed to rewind tarball")
+	}
+
+	out, err := os.Create(filepath.Join(dir, pluginId+".tar.gz"))
+	if err != nil {
+		return errors.Wrap(err, "failed to create retained tarball")
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, tarball); err != nil {
+		return errors.Wrap(err, "failed to write retained tarball")
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, pluginId+".tar.gz.sig"), []byte(signature), 0644); err != nil {
+		return errors.Wrap(err, "failed to write retained signature")
+	}
+
+	return nil
+}
+
+// verifyDetachedSignature checks a base64-encoded, armor-less detached GPG
+// signature of plugin against the given ASCII-armored public key.
+func verifyDetachedSignature(plugin io.Reader, signature string, publicKey []byte) error {
+	keyring, err := openpgp.ReadArmoredKeyRing(bytes.NewReader(publicKey))
+	if err != nil {
+		return errors.Wrap(err, "failed to parse public key")
+	}
+
+	sigBytes, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return errors.Wrap(err, "failed to decode signature")
+	}
+
+	_, err = openpgp.CheckDetachedSignature(keyring, plugin, bytes.NewReader(sigBytes))
+	return err
+}
@@ -0,0 +1,239 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mattermost/mattermost-server/v5/mlog"
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// DiagnosePlugins walks the plugin directory and DB/config state, reporting
+// one PluginDoctorIssue per inconsistency found: orphaned bundles on disk
+// with no corresponding installed plugin, installed plugins with no bundle
+// on disk, plugins enabled in config that failed to activate, and
+// signatures that no longer verify against any installed public key. When
+// fix is true, each issue's remediation is applied: orphaned bundle
+// directories are deleted from disk, and the other issues are handled via the
+// corresponding App method.
+//
+// The original request also asked for a check of "plugin state referencing
+// tenants/teams that no longer exist": PluginSettings has no team-scoped
+// plugin state in this schema (PluginStates is keyed only by plugin id), so
+// there is nothing to reconcile and that check is intentionally not
+// implemented.
+func (a *App) DiagnosePlugins(fix bool) ([]*model.PluginDoctorIssue, *model.AppError) {
+	var issues []*model.PluginDoctorIssue
+
+	pluginsResp, appErr := a.GetPlugins()
+	if appErr != nil {
+		return nil, appErr
+	}
+
+	installed := make(map[string]bool)
+	for _, plugin := range pluginsResp.Active {
+		installed[plugin.Manifest.Id] = true
+	}
+	for _, plugin := range pluginsResp.Inactive {
+		installed[plugin.Manifest.Id] = true
+	}
+
+	issues = append(issues, a.diagnoseOrphanedBundles(installed, fix)...)
+	issues = append(issues, a.diagnoseMissingBundles(pluginsResp, fix)...)
+	issues = append(issues, a.diagnoseFailedActivations(pluginsResp, fix)...)
+	issues = append(issues, a.diagnoseInvalidSignatures(pluginsResp, fix)...)
+
+	return issues, nil
+}
+
+// diagnoseOrphanedBundles reports bundle directories under
+// PluginSettings.Directory that have no corresponding installed plugin row.
+// pluginSignatureDir and any other dot-directory are skipped: they hold
+// doctor/install bookkeeping (retained signed artifacts), not plugin
+// bundles, and must never be swept up as "orphaned".
+func (a *App) diagnoseOrphanedBundles(installed map[string]bool, fix bool) []*model.PluginDoctorIssue {
+	var issues []*model.PluginDoctorIssue
+
+	directory := *a.Config().PluginSettings.Directory
+	entries, err := ioutil.ReadDir(directory)
+	if err != nil {
+		mlog.Warn("plugin doctor: failed to read plugin directory", mlog.Err(err))
+		return issues
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() || installed[entry.Name()] || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		issue := &model.PluginDoctorIssue{
+			Id:          entry.Name(),
+			Severity:    model.PluginDoctorSeverityWarning,
+			Summary:     "orphaned plugin bundle on disk with no matching installed plugin",
+			Remediation: "remove the bundle directory " + filepath.Join(directory, entry.Name()),
+		}
+
+		if fix {
+			if err := os.RemoveAll(filepath.Join(directory, entry.Name())); err != nil {
+				mlog.Error("plugin doctor: failed to remove orphaned bundle", mlog.String("plugin_id", entry.Name()), mlog.Err(err))
+			} else {
+				issue.Fixed = true
+			}
+		}
+
+		issues = append(issues, issue)
+	}
+
+	return issues
+}
+
+// diagnoseMissingBundles reports installed plugins (per GetPlugins) that
+// have no corresponding bundle directory under PluginSettings.Directory —
+// the inverse of diagnoseOrphanedBundles. The bundle can't be recreated
+// from nothing, so --fix removes the DB row instead, matching the
+// remediation text.
+func (a *App) diagnoseMissingBundles(pluginsResp *model.PluginsResponse, fix bool) []*model.PluginDoctorIssue {
+	var issues []*model.PluginDoctorIssue
+
+	directory := *a.Config().PluginSettings.Directory
+	all := append(append([]*model.PluginInfo{}, pluginsResp.Active...), pluginsResp.Inactive...)
+	for _, plugin := range all {
+		info, err := os.Stat(filepath.Join(directory, plugin.Manifest.Id))
+		if err == nil && info.IsDir() {
+			continue
+		}
+
+		issue := &model.PluginDoctorIssue{
+			Id:          plugin.Manifest.Id,
+			Severity:    model.PluginDoctorSeverityError,
+			Summary:     "plugin is installed but has no bundle on disk",
+			Remediation: "remove the plugin, since its bundle can't be restored by the doctor",
+		}
+
+		if fix {
+			if err := a.RemovePlugin(plugin.Manifest.Id); err != nil {
+				mlog.Error("plugin doctor: failed to remove plugin with missing bundle", mlog.String("plugin_id", plugin.Manifest.Id), mlog.Err(err))
+			} else {
+				issue.Fixed = true
+			}
+		}
+
+		issues = append(issues, issue)
+	}
+
+	return issues
+}
+
+// diagnoseFailedActivations reports plugins enabled in config that are not
+// present in the active set returned by GetPlugins.
+func (a *App) diagnoseFailedActivations(pluginsResp *model.PluginsResponse, fix bool) []*model.PluginDoctorIssue {
+	var issues []*model.PluginDoctorIssue
+
+	active := make(map[string]bool)
+	for _, plugin := range pluginsResp.Active {
+		active[plugin.Manifest.Id] = true
+	}
+
+	for pluginId, state := range a.Config().PluginSettings.PluginStates {
+		if state == nil || !state.Enable || active[pluginId] {
+			continue
+		}
+
+		issue := &model.PluginDoctorIssue{
+			Id:          pluginId,
+			Severity:    model.PluginDoctorSeverityError,
+			Summary:     "plugin is enabled in config but failed to activate",
+			Remediation: "disable the plugin so its config state matches reality",
+		}
+
+		if fix {
+			if appErr := a.DisablePlugin(pluginId); appErr != nil {
+				mlog.Error("plugin doctor: failed to disable plugin", mlog.String("plugin_id", pluginId), mlog.Err(appErr))
+			} else {
+				issue.Fixed = true
+			}
+		}
+
+		issues = append(issues, issue)
+	}
+
+	return issues
+}
+
+// diagnoseInvalidSignatures reports installed plugins whose retained,
+// signed tarball (see retainPluginArtifact) no longer verifies against any
+// currently installed public key. Plugins installed without going through a
+// channel (e.g. plain `plugin add`) have no retained artifact and are
+// skipped, since there is nothing on disk to re-verify.
+func (a *App) diagnoseInvalidSignatures(pluginsResp *model.PluginsResponse, fix bool) []*model.PluginDoctorIssue {
+	var issues []*model.PluginDoctorIssue
+
+	all := append(append([]*model.PluginInfo{}, pluginsResp.Active...), pluginsResp.Inactive...)
+	for _, plugin := range all {
+		retained, verified := a.PluginSignatureState(plugin.Manifest.Id)
+		if !retained || verified {
+			continue
+		}
+
+		issue := &model.PluginDoctorIssue{
+			Id:          plugin.Manifest.Id,
+			Severity:    model.PluginDoctorSeverityError,
+			Summary:     "signature no longer verifies against any installed public key",
+			Remediation: "disable the plugin until its signing key is re-installed",
+		}
+
+		if fix {
+			if appErr := a.DisablePlugin(plugin.Manifest.Id); appErr != nil {
+				mlog.Error("plugin doctor: failed to disable unsigned plugin", mlog.String("plugin_id", plugin.Manifest.Id), mlog.Err(appErr))
+			} else {
+				issue.Fixed = true
+			}
+		}
+
+		issues = append(issues, issue)
+	}
+
+	return issues
+}
+
+// PluginSignatureState reports whether pluginId has a retained signed
+// artifact (see retainPluginArtifact) and, if so, whether it currently
+// verifies against any installed public key.
+func (a *App) PluginSignatureState(pluginId string) (retained bool, verified bool) {
+	dir := filepath.Join(*a.Config().PluginSettings.Directory, pluginSignatureDir)
+	tarballPath := filepath.Join(dir, pluginId+".tar.gz")
+
+	signature, err := ioutil.ReadFile(tarballPath + ".sig")
+	if err != nil {
+		return false, false
+	}
+
+	keyNames, appErr := a.GetPluginPublicKeys()
+	if appErr != nil {
+		return true, false
+	}
+
+	for _, keyName := range keyNames {
+		key, err := a.GetPublicKey(keyName)
+		if err != nil {
+			continue
+		}
+
+		f, err := os.Open(tarballPath)
+		if err != nil {
+			continue
+		}
+		err = verifyDetachedSignature(f, string(signature), key)
+		f.Close()
+		if err == nil {
+			return true, true
+		}
+	}
+
+	return true, false
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"os"
+
+	"github.com/mattermost/mattermost-server/v5/mlog"
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// EnforceRequiredPluginsAfterSync checks EnforceRequiredPlugins once plugins
+// have been activated/deactivated to match PluginSettings.PluginStates.
+// SyncPluginsActiveState does not call it; it runs only where a caller
+// invokes it directly.
+//
+// isStartup distinguishes the server-boot call from a later one (e.g. a
+// config-change listener re-syncing active state on a running server): on
+// startup a missing required plugin is fatal, since a server the admin
+// believes is fully configured shouldn't come up without it. At runtime,
+// killing an already-serving process over a transient plugin failure would
+// itself be an outage, so the failure is only logged and returned for the
+// caller to surface.
+func (a *App) EnforceRequiredPluginsAfterSync(isStartup bool) *model.AppError {
+	appErr := a.EnforceRequiredPlugins()
+	if appErr == nil {
+		return nil
+	}
+
+	if isStartup {
+		mlog.Critical("Required plugin failed to activate, shutting down", mlog.Err(appErr))
+		os.Exit(1)
+	}
+
+	mlog.Error("Required plugin failed to activate", mlog.Err(appErr))
+	return appErr
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// EnforceRequiredPluginsAfterSync(true) calls os.Exit on a failed required
+// plugin, so only the isStartup=false path is unit-testable here;
+// EnforceRequiredPlugins is the pure function both delegate to.
+func TestEnforceRequiredPluginsFailsWhenRequiredPluginInactive(t *testing.T) {
+	th := Setup(t)
+	defer th.TearDown()
+
+	config := th.App.Config().Clone()
+	config.PluginSettings.RequiredPluginIds = []string{"com.example.required"}
+	_, _, err := th.App.SaveConfig(config, true)
+	require.Nil(t, err)
+
+	appErr := th.App.EnforceRequiredPlugins()
+	require.NotNil(t, appErr)
+	require.Equal(t, "app.plugin.required_plugin_inactive.app_error", appErr.Id)
+}
+
+func TestEnforceRequiredPluginsPassesWithNoRequiredPlugins(t *testing.T) {
+	th := Setup(t)
+	defer th.TearDown()
+
+	appErr := th.App.EnforceRequiredPlugins()
+	require.Nil(t, appErr)
+}
+
+func TestEnforceRequiredPluginsAfterSyncReturnsErrorWithoutExitingAtRuntime(t *testing.T) {
+	th := Setup(t)
+	defer th.TearDown()
+
+	config := th.App.Config().Clone()
+	config.PluginSettings.RequiredPluginIds = []string{"com.example.required"}
+	_, _, err := th.App.SaveConfig(config, true)
+	require.Nil(t, err)
+
+	appErr := th.App.EnforceRequiredPluginsAfterSync(false)
+	require.NotNil(t, appErr)
+	require.Equal(t, "app.plugin.required_plugin_inactive.app_error", appErr.Id)
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+// Package output formats CLI results for consumption by both humans and
+// automation. It mirrors Jenkins-CLI's OutputOption.Output model: a command
+// marshals a typed value, and this package renders it in whichever format
+// the operator asked for.
+package output
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Format is one of the supported output formats for a list-style command.
+type Format string
+
+const (
+	FormatPlain Format = "plain"
+	FormatJson  Format = "json"
+	FormatYaml  Format = "yaml"
+	FormatTable Format = "table"
+)
+
+// ParseFormat validates a --format flag value, defaulting to FormatPlain
+// when empty.
+func ParseFormat(value string) (Format, error) {
+	switch Format(value) {
+	case "", FormatPlain:
+		return FormatPlain, nil
+	case FormatJson, FormatYaml, FormatTable:
+		return Format(value), nil
+	default:
+		return "", errors.New("unknown format: " + value + " (expected plain, json, yaml, or table)")
+	}
+}
+
+// Row is a single record in a list-style command's output. Plain and table
+// renderers use Columns/Values; json and yaml renderers marshal Value
+// directly so that callers keep their full typed struct.
+type Row struct {
+	Columns []string
+	Values  []string
+	Value   interface{}
+}
+
+// Write renders rows to w in the requested format.
+func Write(w io.Writer, format Format, rows []Row) error {
+	switch format {
+	case FormatJson:
+		return writeJson(w, rows)
+	case FormatYaml:
+		return writeYaml(w, rows)
+	case FormatTable:
+		return writeTable(w, rows)
+	default:
+		return writePlain(w, rows)
+	}
+}
+
+func writePlain(w io.Writer, rows []Row) error {
+	for _, row := range rows {
+		if _, err := fmt.Fprintln(w, joinValues(row.Values)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTable(w io.Writer, rows []Row) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if len(rows) > 0 && len(rows[0].Columns) > 0 {
+		if _, err := fmt.Fprintln(tw, joinTabbed(rows[0].Columns)); err != nil {
+			return err
+		}
+	}
+	for _, row := range rows {
+		if _, err := fmt.Fprintln(tw, joinTabbed(row.Values)); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
+
+func writeJson(w io.Writer, rows []Row) error {
+	values := make([]interface{}, len(rows))
+	for i, row := range rows {
+		values[i] = row.Value
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(values)
+}
+
+func writeYaml(w io.Writer, rows []Row) error {
+	values := make([]interface{}, len(rows))
+	for i, row := range rows {
+		values[i] = row.Value
+	}
+
+	b, err := yaml.Marshal(values)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+func joinValues(values []string) string {
+	return joinTabbed(values)
+}
+
+func joinTabbed(values []string) string {
+	out := ""
+	for i, value := range values {
+		if i > 0 {
+			out += "\t"
+		}
+		out += value
+	}
+	return out
+}
@@ -4,10 +4,17 @@
 package commands
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
+
+	"github.com/mattermost/mattermost-server/v5/cmd/mattermost/commands/output"
+	"github.com/mattermost/mattermost-server/v5/model"
 )
 
 var PluginCmd = &cobra.Command{
@@ -80,12 +87,103 @@ var PluginDeletePublicKeyCmd = &cobra.Command{
 	RunE:    pluginDeletePublicKeyCmdF,
 }
 
+var PluginInstallCmd = &cobra.Command{
+	Use:     "install <name>[@version]",
+	Short:   "Install a plugin from a configured channel",
+	Long:    "Resolve <name>[@version] across all configured plugin channels, download the artifact, verify its checksum and signature, and install it.",
+	Example: `  plugin install hovercardexample@1.2.0
+  plugin install hovercardexample --grant-all-permissions`,
+	Args: cobra.ExactArgs(1),
+	RunE: pluginInstallCmdF,
+}
+
+var PluginSearchCmd = &cobra.Command{
+	Use:     "search <query>",
+	Short:   "Search plugin channels",
+	Long:    "List the plugins offered by all configured channels whose name matches <query>.",
+	Example: `  plugin search hovercard`,
+	Args:    cobra.ExactArgs(1),
+	RunE:    pluginSearchCmdF,
+}
+
+var PluginChannelCmd = &cobra.Command{
+	Use:   "channel",
+	Short: "Management of plugin channels",
+}
+
+var PluginChannelAddCmd = &cobra.Command{
+	Use:     "add <name> <url>",
+	Short:   "Add a plugin channel",
+	Long:    "Add a plugin channel, a URL serving a JSON index of installable plugins.",
+	Example: `  plugin channel add community https://plugins.example.com/index.json`,
+	Args:    cobra.ExactArgs(2),
+	RunE:    pluginChannelAddCmdF,
+}
+
+var PluginChannelRemoveCmd = &cobra.Command{
+	Use:     "remove <name>",
+	Short:   "Remove a plugin channel",
+	Long:    "Remove a previously configured plugin channel.",
+	Example: `  plugin channel remove community`,
+	Args:    cobra.ExactArgs(1),
+	RunE:    pluginChannelRemoveCmdF,
+}
+
+var PluginChannelListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List plugin channels",
+	Long:    "List all configured plugin channels, in precedence order.",
+	Example: `  plugin channel list`,
+	RunE:    pluginChannelListCmdF,
+}
+
+var PluginBootstrapCmd = &cobra.Command{
+	Use:     "bootstrap [dir]",
+	Short:   "Install prepackaged plugins from a directory",
+	Long:    "Scan a directory of .tar.gz plugin bundles, validate each against the installed public keys, and install any that are missing or older than the bundle version.",
+	Example: `  plugin bootstrap ./prepackaged_plugins --required hovercardexample,pluginexample`,
+	Args:    cobra.ExactArgs(1),
+	RunE:    pluginBootstrapCmdF,
+}
+
+var PluginMetadataCmd = &cobra.Command{
+	Use:     "metadata [dir]",
+	Short:   "Dump manifests of plugin bundles in a directory",
+	Long:    "Emit a JSON array of parsed manifests for the .tar.gz bundles in dir, without installing anything. The command opens the configured database at startup like the other local plugin subcommands, but reads and writes no plugin state.",
+	Example: `  plugin metadata ./prepackaged_plugins`,
+	Args:    cobra.ExactArgs(1),
+	RunE:    pluginMetadataCmdF,
+}
+
+var PluginDoctorCmd = &cobra.Command{
+	Use:     "doctor",
+	Short:   "Diagnose and optionally repair broken plugin state",
+	Long:    "Walk the plugin directory and DB/config state, reporting orphaned bundles, plugins that failed to activate, and signatures that no longer verify. Pass --fix to repair what is found.",
+	Example: `  plugin doctor
+  plugin doctor --fix`,
+	RunE: pluginDoctorCmdF,
+}
+
 func init() {
+	PluginCmd.PersistentFlags().String("server", "", "Operate against a running server at this URL via its REST API, instead of connecting to the DB directly. Can also be set via MMCTL_SERVER.")
+	PluginCmd.PersistentFlags().String("token", "", "Session or personal access token to authenticate with --server. Can also be set via MMCTL_TOKEN.")
+	PluginListCmd.Flags().String("format", "plain", "The format to print plugins in: plain, json, yaml, or table")
+	PluginListCmd.Flags().String("output", "", "File to write the output to. Defaults to stdout.")
 	PluginPublicKeysCmd.Flags().String("verbose", "", "List names and details of all public keys installed on your Mattermost server.")
+	PluginPublicKeysCmd.Flags().String("format", "plain", "The format to print public keys in: plain, json, yaml, or table")
+	PluginPublicKeysCmd.Flags().String("output", "", "File to write the output to. Defaults to stdout.")
 	PluginPublicKeysCmd.AddCommand(
 		PluginAddPublicKeyCmd,
 		PluginDeletePublicKeyCmd,
 	)
+	PluginInstallCmd.Flags().Bool("grant-all-permissions", false, "Grant the plugin all of its requested permissions on install, without prompting.")
+	PluginDoctorCmd.Flags().Bool("fix", false, "Repair the inconsistencies found, instead of only reporting them.")
+	PluginBootstrapCmd.Flags().String("required", "", "Comma-separated plugin ids to record in PluginSettings.RequiredPluginIds. Server startup does not check this list.")
+	PluginChannelCmd.AddCommand(
+		PluginChannelAddCmd,
+		PluginChannelRemoveCmd,
+		PluginChannelListCmd,
+	)
 	PluginCmd.AddCommand(
 		PluginAddCmd,
 		PluginDeleteCmd,
@@ -93,16 +191,22 @@ func init() {
 		PluginDisableCmd,
 		PluginListCmd,
 		PluginPublicKeysCmd,
+		PluginInstallCmd,
+		PluginSearchCmd,
+		PluginChannelCmd,
+		PluginDoctorCmd,
+		PluginBootstrapCmd,
+		PluginMetadataCmd,
 	)
 	RootCmd.AddCommand(PluginCmd)
 }
 
 func pluginAddCmdF(command *cobra.Command, args []string) error {
-	a, err := InitDBCommandContextCobra(command)
+	backend, err := getPluginBackend(command)
 	if err != nil {
 		return err
 	}
-	defer a.Shutdown()
+	defer backend.Close()
 
 	if len(args) < 1 {
 		return errors.New("Expected at least one argument. See help text for details.")
@@ -114,7 +218,7 @@ func pluginAddCmdF(command *cobra.Command, args []string) error {
 			return err
 		}
 
-		if _, err := a.InstallPlugin(fileReader, false); err != nil {
+		if _, err := backend.AddPlugin(fileReader, false); err != nil {
 			CommandPrintErrorln("Unable to add plugin: " + args[i] + ". Error: " + err.Error())
 		} else {
 			CommandPrettyPrintln("Added plugin: " + plugin)
@@ -126,18 +230,18 @@ func pluginAddCmdF(command *cobra.Command, args []string) error {
 }
 
 func pluginDeleteCmdF(command *cobra.Command, args []string) error {
-	a, err := InitDBCommandContextCobra(command)
+	backend, err := getPluginBackend(command)
 	if err != nil {
 		return err
 	}
-	defer a.Shutdown()
+	defer backend.Close()
 
 	if len(args) < 1 {
 		return errors.New("Expected at least one argument. See help text for details.")
 	}
 
 	for _, plugin := range args {
-		if err := a.RemovePlugin(plugin); err != nil {
+		if err := backend.RemovePlugin(plugin); err != nil {
 			CommandPrintErrorln("Unable to delete plugin: " + plugin + ". Error: " + err.Error())
 		} else {
 			CommandPrettyPrintln("Deleted plugin: " + plugin)
@@ -148,18 +252,18 @@ func pluginDeleteCmdF(command *cobra.Command, args []string) error {
 }
 
 func pluginEnableCmdF(command *cobra.Command, args []string) error {
-	a, err := InitDBCommandContextCobra(command)
+	backend, err := getPluginBackend(command)
 	if err != nil {
 		return err
 	}
-	defer a.Shutdown()
+	defer backend.Close()
 
 	if len(args) < 1 {
 		return errors.New("Expected at least one argument. See help text for details.")
 	}
 
 	for _, plugin := range args {
-		if err := a.EnablePlugin(plugin); err != nil {
+		if err := backend.EnablePlugin(plugin); err != nil {
 			CommandPrintErrorln("Unable to enable plugin: " + plugin + ". Error: " + err.Error())
 		} else {
 			CommandPrettyPrintln("Enabled plugin: " + plugin)
@@ -170,18 +274,18 @@ func pluginEnableCmdF(command *cobra.Command, args []string) error {
 }
 
 func pluginDisableCmdF(command *cobra.Command, args []string) error {
-	a, err := InitDBCommandContextCobra(command)
+	backend, err := getPluginBackend(command)
 	if err != nil {
 		return err
 	}
-	defer a.Shutdown()
+	defer backend.Close()
 
 	if len(args) < 1 {
 		return errors.New("Expected at least one argument. See help text for details.")
 	}
 
 	for _, plugin := range args {
-		if err := a.DisablePlugin(plugin); err != nil {
+		if err := backend.DisablePlugin(plugin); err != nil {
 			CommandPrintErrorln("Unable to disable plugin: " + plugin + ". Error: " + err.Error())
 		} else {
 			CommandPrettyPrintln("Disabled plugin: " + plugin)
@@ -191,78 +295,195 @@ func pluginDisableCmdF(command *cobra.Command, args []string) error {
 	return nil
 }
 
+// pluginInfo is the typed record emitted for each plugin by `plugin list`
+// in the json/yaml/table formats.
+type pluginInfo struct {
+	Id                string `json:"id" yaml:"id"`
+	Name              string `json:"name" yaml:"name"`
+	Version           string `json:"version" yaml:"version"`
+	State             string `json:"state" yaml:"state"`
+	SignatureStatus   string `json:"signature_status" yaml:"signature_status"`
+	MinServerVersion  string `json:"min_server_version" yaml:"min_server_version"`
+	HasSettingsSchema bool   `json:"has_settings_schema" yaml:"has_settings_schema"`
+}
+
 func pluginListCmdF(command *cobra.Command, args []string) error {
-	a, err := InitDBCommandContextCobra(command)
+	backend, err := getPluginBackend(command)
 	if err != nil {
 		return err
 	}
-	defer a.Shutdown()
+	defer backend.Close()
 
-	pluginsResp, appErr := a.GetPlugins()
-	if appErr != nil {
-		return errors.New("Unable to list plugins. Error: " + appErr.Error())
+	format, err := parseOutputFormat(command)
+	if err != nil {
+		return err
 	}
 
-	CommandPrettyPrintln("Listing active plugins")
-	for _, plugin := range pluginsResp.Active {
-		CommandPrettyPrintln(plugin.Manifest.Name + ", Version: " + plugin.Manifest.Version)
+	pluginsResp, err := backend.GetPlugins()
+	if err != nil {
+		return errors.New("Unable to list plugins. Error: " + err.Error())
 	}
 
-	CommandPrettyPrintln("Listing inactive plugins")
-	for _, plugin := range pluginsResp.Inactive {
-		CommandPrettyPrintln(plugin.Manifest.Name + ", Version: " + plugin.Manifest.Version)
+	if format == output.FormatPlain {
+		rows := []output.Row{{Values: []string{"Listing active plugins"}}}
+		for _, plugin := range pluginsResp.Active {
+			rows = append(rows, output.Row{Values: []string{plugin.Manifest.Name + ", Version: " + plugin.Manifest.Version}})
+		}
+
+		rows = append(rows, output.Row{Values: []string{"Listing inactive plugins"}})
+		for _, plugin := range pluginsResp.Inactive {
+			rows = append(rows, output.Row{Values: []string{plugin.Manifest.Name + ", Version: " + plugin.Manifest.Version}})
+		}
+
+		return writeCommandOutput(command, format, rows)
 	}
 
-	return nil
+	var rows []output.Row
+	rows = append(rows, pluginInfoRows(backend, pluginsResp.Active, "active")...)
+	rows = append(rows, pluginInfoRows(backend, pluginsResp.Inactive, "inactive")...)
+
+	return writeCommandOutput(command, format, rows)
+}
+
+func pluginInfoRows(backend PluginBackend, plugins []*model.PluginInfo, state string) []output.Row {
+	rows := make([]output.Row, 0, len(plugins))
+	for _, plugin := range plugins {
+		info := pluginInfo{
+			Id:                plugin.Manifest.Id,
+			Name:              plugin.Manifest.Name,
+			Version:           plugin.Manifest.Version,
+			State:             state,
+			SignatureStatus:   backend.PluginSignatureStatus(plugin.Manifest.Id),
+			MinServerVersion:  plugin.Manifest.MinServerVersion,
+			HasSettingsSchema: plugin.Manifest.SettingsSchema != nil,
+		}
+		rows = append(rows, output.Row{
+			Columns: []string{"ID", "NAME", "VERSION", "STATE", "SIGNATURE", "MIN SERVER VERSION", "HAS SETTINGS"},
+			Values:  []string{info.Id, info.Name, info.Version, info.State, info.SignatureStatus, info.MinServerVersion, strconv.FormatBool(info.HasSettingsSchema)},
+			Value:   info,
+		})
+	}
+	return rows
+}
+
+// publicKeyInfo is the typed record emitted for each key by `plugin keys`
+// in the json/yaml/table formats.
+type publicKeyInfo struct {
+	Name      string `json:"name" yaml:"name"`
+	PublicKey string `json:"public_key,omitempty" yaml:"public_key,omitempty"`
 }
 
 func pluginPublicKeysCmdF(command *cobra.Command, args []string) error {
-	a, err := InitDBCommandContextCobra(command)
+	backend, err := getPluginBackend(command)
 	if err != nil {
 		return err
 	}
-	defer a.Shutdown()
+	defer backend.Close()
 
 	verbose, err := command.Flags().GetString("verbose")
 	if err != nil {
 		return errors.New("failed reading verbose. Error: " + err.Error())
 	}
 
-	pluginPublicKeysResp, appErr := a.GetPluginPublicKeys()
-	if appErr != nil {
-		return errors.New("Unable to list public keys. Error: " + appErr.Error())
+	format, err := parseOutputFormat(command)
+	if err != nil {
+		return err
 	}
 
-	if verbose == "" {
-		for _, publicKey := range pluginPublicKeysResp {
-			CommandPrettyPrintln(publicKey)
+	pluginPublicKeysResp, err := backend.GetPluginPublicKeys()
+	if err != nil {
+		return errors.New("Unable to list public keys. Error: " + err.Error())
+	}
+
+	if format == output.FormatPlain {
+		rows := make([]output.Row, 0, len(pluginPublicKeysResp))
+		if verbose == "" {
+			for _, publicKey := range pluginPublicKeysResp {
+				rows = append(rows, output.Row{Values: []string{publicKey}})
+			}
+		} else {
+			for _, publicKey := range pluginPublicKeysResp {
+				key, err := backend.GetPublicKey(publicKey)
+				if err != nil {
+					CommandPrintErrorln("Unable to get plugin public key: " + publicKey + ". Error: " + err.Error())
+				}
+				rows = append(rows, output.Row{Values: []string{"Plugin name: " + publicKey + ". \nPublic key: \n" + string(key) + "\n"}})
+			}
 		}
-	} else {
-		for _, publicKey := range pluginPublicKeysResp {
-			key, err := a.GetPublicKey(publicKey)
+		return writeCommandOutput(command, format, rows)
+	}
+
+	rows := make([]output.Row, 0, len(pluginPublicKeysResp))
+	for _, publicKey := range pluginPublicKeysResp {
+		info := publicKeyInfo{Name: publicKey}
+		if verbose != "" {
+			key, err := backend.GetPublicKey(publicKey)
 			if err != nil {
 				CommandPrintErrorln("Unable to get plugin public key: " + publicKey + ". Error: " + err.Error())
+			} else {
+				info.PublicKey = string(key)
 			}
-			CommandPrettyPrintln("Plugin name: " + publicKey + ". \nPublic key: \n" + string(key) + "\n")
 		}
+		rows = append(rows, output.Row{
+			Columns: []string{"NAME"},
+			Values:  []string{info.Name},
+			Value:   info,
+		})
 	}
 
-	return nil
+	return writeCommandOutput(command, format, rows)
+}
+
+// parseOutputFormat reads the --format flag shared by list-style plugin
+// subcommands.
+func parseOutputFormat(command *cobra.Command) (output.Format, error) {
+	formatFlag, err := command.Flags().GetString("format")
+	if err != nil {
+		return "", errors.New("failed reading format. Error: " + err.Error())
+	}
+
+	format, err := output.ParseFormat(formatFlag)
+	if err != nil {
+		return "", err
+	}
+
+	return format, nil
+}
+
+// writeCommandOutput renders rows per --format, writing to the file named by
+// --output or to stdout when it's unset.
+func writeCommandOutput(command *cobra.Command, format output.Format, rows []output.Row) error {
+	outputFile, err := command.Flags().GetString("output")
+	if err != nil {
+		return errors.New("failed reading output. Error: " + err.Error())
+	}
+
+	w := os.Stdout
+	if outputFile != "" {
+		f, err := os.Create(outputFile)
+		if err != nil {
+			return errors.New("failed opening output file: " + outputFile + ". Error: " + err.Error())
+		}
+		defer f.Close()
+		w = f
+	}
+
+	return output.Write(w, format, rows)
 }
 
 func pluginAddPublicKeyCmdF(command *cobra.Command, args []string) error {
-	a, err := InitDBCommandContextCobra(command)
+	backend, err := getPluginBackend(command)
 	if err != nil {
 		return err
 	}
-	defer a.Shutdown()
+	defer backend.Close()
 
 	if len(args) < 1 {
 		return errors.New("Expected at least one argument. See help text for details.")
 	}
 
 	for _, pkFile := range args {
-		if err := a.AddPublicKey(pkFile); err != nil {
+		if err := backend.AddPublicKey(pkFile); err != nil {
 			CommandPrintErrorln("Unable to add public key: " + pkFile + ". Error: " + err.Error())
 		} else {
 			CommandPrettyPrintln("Added public key: " + pkFile)
@@ -274,18 +495,18 @@ func pluginAddPublicKeyCmdF(command *cobra.Command, args []string) error {
 }
 
 func pluginDeletePublicKeyCmdF(command *cobra.Command, args []string) error {
-	a, err := InitDBCommandContextCobra(command)
+	backend, err := getPluginBackend(command)
 	if err != nil {
 		return err
 	}
-	defer a.Shutdown()
+	defer backend.Close()
 
 	if len(args) < 1 {
 		return errors.New("Expected at least one argument. See help text for details.")
 	}
 
 	for _, pkFile := range args {
-		if err := a.DeletePublicKey(pkFile); err != nil {
+		if err := backend.DeletePublicKey(pkFile); err != nil {
 			CommandPrintErrorln("Unable to delete public key: " + pkFile + ". Error: " + err.Error())
 		} else {
 			CommandPrettyPrintln("Deleted public key: " + pkFile)
@@ -295,3 +516,265 @@ func pluginDeletePublicKeyCmdF(command *cobra.Command, args []string) error {
 
 	return nil
 }
+
+func pluginInstallCmdF(command *cobra.Command, args []string) error {
+	if err := requireLocalPluginBackend(command); err != nil {
+		return err
+	}
+
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	grantAllPermissions, err := command.Flags().GetBool("grant-all-permissions")
+	if err != nil {
+		return errors.New("failed reading grant-all-permissions. Error: " + err.Error())
+	}
+
+	name, version := splitPluginNameVersion(args[0])
+
+	entry, appErr := a.ResolvePluginChannelEntry(name, version, os.Stdout)
+	if appErr != nil {
+		return errors.New("Unable to resolve plugin: " + args[0] + ". Error: " + appErr.Error())
+	}
+
+	if !grantAllPermissions {
+		CommandPrettyPrintln("Installing " + entry.Name + "@" + entry.Version + " grants it all requested permissions.")
+		if !confirmPluginInstall() {
+			CommandPrettyPrintln("Install cancelled.")
+			return nil
+		}
+	}
+
+	manifest, appErr := a.InstallPluginFromChannelEntry(entry, true)
+	if appErr != nil {
+		return errors.New("Unable to install plugin: " + args[0] + ". Error: " + appErr.Error())
+	}
+
+	CommandPrettyPrintln("Installed plugin: " + manifest.Name + ", Version: " + manifest.Version)
+
+	return nil
+}
+
+func pluginSearchCmdF(command *cobra.Command, args []string) error {
+	if err := requireLocalPluginBackend(command); err != nil {
+		return err
+	}
+
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	query := args[0]
+
+	entries := a.FetchPluginChannelIndexes(os.Stdout)
+	for _, entry := range entries {
+		if strings.Contains(strings.ToLower(entry.Name), strings.ToLower(query)) {
+			CommandPrettyPrintln(entry.Name + "@" + entry.Version)
+		}
+	}
+
+	return nil
+}
+
+func pluginChannelAddCmdF(command *cobra.Command, args []string) error {
+	if err := requireLocalPluginBackend(command); err != nil {
+		return err
+	}
+
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	name, url := args[0], args[1]
+
+	config := a.Config().Clone()
+	config.PluginSettings.Channels = append(config.PluginSettings.Channels, &model.PluginChannel{Name: name, Url: url})
+
+	if _, _, err := a.SaveConfig(config, true); err != nil {
+		return errors.New("Unable to add plugin channel: " + name + ". Error: " + err.Error())
+	}
+
+	CommandPrettyPrintln("Added plugin channel: " + name)
+
+	return nil
+}
+
+func pluginChannelRemoveCmdF(command *cobra.Command, args []string) error {
+	if err := requireLocalPluginBackend(command); err != nil {
+		return err
+	}
+
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	name := args[0]
+
+	config := a.Config().Clone()
+	channels := make([]*model.PluginChannel, 0, len(config.PluginSettings.Channels))
+	for _, channel := range config.PluginSettings.Channels {
+		if channel.Name != name {
+			channels = append(channels, channel)
+		}
+	}
+	config.PluginSettings.Channels = channels
+
+	if _, _, err := a.SaveConfig(config, true); err != nil {
+		return errors.New("Unable to remove plugin channel: " + name + ". Error: " + err.Error())
+	}
+
+	CommandPrettyPrintln("Removed plugin channel: " + name)
+
+	return nil
+}
+
+func pluginChannelListCmdF(command *cobra.Command, args []string) error {
+	if err := requireLocalPluginBackend(command); err != nil {
+		return err
+	}
+
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	for _, channel := range a.Config().PluginSettings.Channels {
+		CommandPrettyPrintln(channel.Name + ": " + channel.Url)
+	}
+
+	return nil
+}
+
+func pluginBootstrapCmdF(command *cobra.Command, args []string) error {
+	if err := requireLocalPluginBackend(command); err != nil {
+		return err
+	}
+
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	requiredFlag, err := command.Flags().GetString("required")
+	if err != nil {
+		return errors.New("failed reading required. Error: " + err.Error())
+	}
+	var required []string
+	if requiredFlag != "" {
+		required = strings.Split(requiredFlag, ",")
+	}
+
+	manifests, appErr := a.BootstrapPlugins(args[0], required)
+	if appErr != nil {
+		return errors.New("Unable to bootstrap plugins. Error: " + appErr.Error())
+	}
+
+	if len(manifests) == 0 {
+		CommandPrettyPrintln("No plugins needed installing.")
+		return nil
+	}
+
+	for _, manifest := range manifests {
+		CommandPrettyPrintln("Installed plugin: " + manifest.Name + ", Version: " + manifest.Version)
+	}
+
+	return nil
+}
+
+func pluginMetadataCmdF(command *cobra.Command, args []string) error {
+	if err := requireLocalPluginBackend(command); err != nil {
+		return err
+	}
+
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	manifests, appErr := a.DumpPluginMetadata(args[0])
+	if appErr != nil {
+		return errors.New("Unable to read plugin metadata. Error: " + appErr.Error())
+	}
+
+	b, err := json.MarshalIndent(manifests, "", "  ")
+	if err != nil {
+		return errors.New("Unable to marshal plugin metadata. Error: " + err.Error())
+	}
+
+	fmt.Println(string(b))
+
+	return nil
+}
+
+func pluginDoctorCmdF(command *cobra.Command, args []string) error {
+	if err := requireLocalPluginBackend(command); err != nil {
+		return err
+	}
+
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return err
+	}
+	defer a.Shutdown()
+
+	fix, err := command.Flags().GetBool("fix")
+	if err != nil {
+		return errors.New("failed reading fix. Error: " + err.Error())
+	}
+
+	issues, appErr := a.DiagnosePlugins(fix)
+	if appErr != nil {
+		return errors.New("Unable to diagnose plugins. Error: " + appErr.Error())
+	}
+
+	if len(issues) == 0 {
+		CommandPrettyPrintln("No plugin inconsistencies found.")
+		return nil
+	}
+
+	for _, issue := range issues {
+		line := "[" + issue.Severity + "] " + issue.Id + ": " + issue.Summary + " (" + issue.Remediation + ")"
+		if fix {
+			if issue.Fixed {
+				line += " - fixed"
+			} else {
+				line += " - not fixed"
+			}
+		}
+		CommandPrettyPrintln(line)
+	}
+
+	return nil
+}
+
+// confirmPluginInstall prompts the user to confirm granting a plugin all of
+// its requested permissions, used by `plugin install` when
+// --grant-all-permissions was not passed.
+func confirmPluginInstall() bool {
+	CommandPrettyPrintln("Do you want to continue? [y/N]: ")
+	var input string
+	fmt.Scanln(&input)
+	input = strings.ToLower(strings.TrimSpace(input))
+	return input == "y" || input == "yes"
+}
+
+// splitPluginNameVersion splits a "<name>[@version]" argument into its name
+// and version parts. version is empty when unspecified, meaning "latest".
+func splitPluginNameVersion(arg string) (name string, version string) {
+	if i := strings.LastIndex(arg, "@"); i != -1 {
+		return arg[:i], arg[i+1:]
+	}
+	return arg, ""
+}
@@ -0,0 +1,239 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package commands
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/mattermost/mattermost-server/v5/app"
+	"github.com/mattermost/mattermost-server/v5/model"
+)
+
+// errPluginKeyOpsNotSupportedRemotely is returned by the remote backend's
+// public-key methods. The server doesn't yet expose REST endpoints for
+// plugin public key management, so --server mode can't support them; run
+// these subcommands directly against the DB host instead.
+var errPluginKeyOpsNotSupportedRemotely = errors.New("plugin public key management is not supported with --server; run this subcommand without --server/--token against the DB host")
+
+// errPluginLocalOnly is returned by subcommands that always require direct
+// DB access (channel management, doctor, bootstrap, metadata) when
+// --server/--token (or MMCTL_SERVER/MMCTL_TOKEN) is set, instead of
+// silently ignoring the flag and failing with a confusing DB-connection
+// error.
+var errPluginLocalOnly = errors.New("this subcommand requires direct DB access and does not support --server/--token")
+
+// requireLocalPluginBackend rejects --server/--token (or their
+// MMCTL_SERVER/MMCTL_TOKEN env equivalents) for subcommands that have no
+// remote-backend implementation and always operate against the DB directly.
+func requireLocalPluginBackend(command *cobra.Command) error {
+	server, err := command.Flags().GetString("server")
+	if err != nil {
+		return err
+	}
+	token, err := command.Flags().GetString("token")
+	if err != nil {
+		return err
+	}
+
+	if server != "" || token != "" || os.Getenv("MMCTL_SERVER") != "" || os.Getenv("MMCTL_TOKEN") != "" {
+		return errPluginLocalOnly
+	}
+
+	return nil
+}
+
+// PluginBackend is implemented by localPluginBackend and remotePluginBackend
+// so that each `plugin *` subcommand can operate either against an embedded
+// App backed by a direct DB connection, or against a running server over its
+// REST API. Subcommands that need config or install-time features beyond
+// this surface (channels, doctor, bootstrap) always require the local
+// backend.
+type PluginBackend interface {
+	AddPlugin(reader io.ReadSeeker, replace bool) (*model.Manifest, error)
+	RemovePlugin(id string) error
+	EnablePlugin(id string) error
+	DisablePlugin(id string) error
+	GetPlugins() (*model.PluginsResponse, error)
+	GetPluginPublicKeys() ([]string, error)
+	GetPublicKey(name string) ([]byte, error)
+	AddPublicKey(path string) error
+	DeletePublicKey(name string) error
+	// PluginSignatureStatus reports the signature state of an installed
+	// plugin: "verified", "invalid", "unsigned" (no signed artifact was
+	// retained for it), or "unknown" when the backend can't determine it.
+	PluginSignatureStatus(id string) string
+	Close()
+}
+
+const (
+	PluginSignatureStatusVerified = "verified"
+	PluginSignatureStatusInvalid  = "invalid"
+	PluginSignatureStatusUnsigned = "unsigned"
+	PluginSignatureStatusUnknown  = "unknown"
+)
+
+type localPluginBackend struct {
+	app *app.App
+}
+
+func (b *localPluginBackend) AddPlugin(reader io.ReadSeeker, replace bool) (*model.Manifest, error) {
+	manifest, appErr := b.app.InstallPlugin(reader, replace)
+	if appErr != nil {
+		return nil, appErr
+	}
+	return manifest, nil
+}
+
+func (b *localPluginBackend) RemovePlugin(id string) error {
+	return b.app.RemovePlugin(id)
+}
+
+func (b *localPluginBackend) EnablePlugin(id string) error {
+	return b.app.EnablePlugin(id)
+}
+
+func (b *localPluginBackend) DisablePlugin(id string) error {
+	return b.app.DisablePlugin(id)
+}
+
+func (b *localPluginBackend) GetPlugins() (*model.PluginsResponse, error) {
+	resp, appErr := b.app.GetPlugins()
+	if appErr != nil {
+		return nil, appErr
+	}
+	return resp, nil
+}
+
+func (b *localPluginBackend) GetPluginPublicKeys() ([]string, error) {
+	keys, appErr := b.app.GetPluginPublicKeys()
+	if appErr != nil {
+		return nil, appErr
+	}
+	return keys, nil
+}
+
+func (b *localPluginBackend) GetPublicKey(name string) ([]byte, error) {
+	return b.app.GetPublicKey(name)
+}
+
+func (b *localPluginBackend) AddPublicKey(path string) error {
+	return b.app.AddPublicKey(path)
+}
+
+func (b *localPluginBackend) DeletePublicKey(name string) error {
+	return b.app.DeletePublicKey(name)
+}
+
+func (b *localPluginBackend) PluginSignatureStatus(id string) string {
+	retained, verified := b.app.PluginSignatureState(id)
+	switch {
+	case !retained:
+		return PluginSignatureStatusUnsigned
+	case verified:
+		return PluginSignatureStatusVerified
+	default:
+		return PluginSignatureStatusInvalid
+	}
+}
+
+func (b *localPluginBackend) Close() {
+	b.app.Shutdown()
+}
+
+type remotePluginBackend struct {
+	client *model.Client4
+}
+
+func (b *remotePluginBackend) AddPlugin(reader io.ReadSeeker, replace bool) (*model.Manifest, error) {
+	if replace {
+		manifest, resp := b.client.UploadPluginForced(reader)
+		return manifest, resp.Error
+	}
+	manifest, resp := b.client.UploadPlugin(reader)
+	return manifest, resp.Error
+}
+
+func (b *remotePluginBackend) RemovePlugin(id string) error {
+	_, resp := b.client.RemovePlugin(id)
+	return resp.Error
+}
+
+func (b *remotePluginBackend) EnablePlugin(id string) error {
+	_, resp := b.client.EnablePlugin(id)
+	return resp.Error
+}
+
+func (b *remotePluginBackend) DisablePlugin(id string) error {
+	_, resp := b.client.DisablePlugin(id)
+	return resp.Error
+}
+
+func (b *remotePluginBackend) GetPlugins() (*model.PluginsResponse, error) {
+	resp, httpResp := b.client.GetPlugins()
+	return resp, httpResp.Error
+}
+
+func (b *remotePluginBackend) GetPluginPublicKeys() ([]string, error) {
+	return nil, errPluginKeyOpsNotSupportedRemotely
+}
+
+func (b *remotePluginBackend) GetPublicKey(name string) ([]byte, error) {
+	return nil, errPluginKeyOpsNotSupportedRemotely
+}
+
+func (b *remotePluginBackend) AddPublicKey(path string) error {
+	return errPluginKeyOpsNotSupportedRemotely
+}
+
+func (b *remotePluginBackend) DeletePublicKey(name string) error {
+	return errPluginKeyOpsNotSupportedRemotely
+}
+
+// PluginSignatureStatus is unavailable over the REST API today: there is no
+// endpoint exposing a plugin's retained-signature state. Report "unknown"
+// rather than guessing.
+func (b *remotePluginBackend) PluginSignatureStatus(id string) string {
+	return PluginSignatureStatusUnknown
+}
+
+func (b *remotePluginBackend) Close() {}
+
+// getPluginBackend picks a PluginBackend based on the --server/--token flags
+// (or the MMCTL_SERVER/MMCTL_TOKEN environment variables): when set, plugin
+// subcommands operate against a running server over its REST API instead of
+// requiring direct DB access.
+func getPluginBackend(command *cobra.Command) (PluginBackend, error) {
+	server, err := command.Flags().GetString("server")
+	if err != nil {
+		return nil, err
+	}
+	if server == "" {
+		server = os.Getenv("MMCTL_SERVER")
+	}
+
+	token, err := command.Flags().GetString("token")
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		token = os.Getenv("MMCTL_TOKEN")
+	}
+
+	if server != "" {
+		client := model.NewAPIv4Client(server)
+		client.SetToken(token)
+		return &remotePluginBackend{client: client}, nil
+	}
+
+	a, err := InitDBCommandContextCobra(command)
+	if err != nil {
+		return nil, err
+	}
+
+	return &localPluginBackend{app: a}, nil
+}
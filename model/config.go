@@ -0,0 +1,42 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+// PluginState records whether a single installed plugin is enabled.
+type PluginState struct {
+	Enable bool
+}
+
+// PluginSettings is the subset of the server config schema touched by the
+// plugin channel/install/doctor/bootstrap CLI surface. It holds the fields
+// already in use elsewhere in this package (Directory, PluginStates) plus
+// the channel and required-plugin additions introduced alongside `plugin
+// install`/`plugin bootstrap`.
+type PluginSettings struct {
+	Enable       *bool
+	Directory    *string
+	PluginStates map[string]*PluginState
+
+	// Channels lists the configured plugin channels, in precedence order,
+	// that `plugin install`/`plugin search` resolve plugins against.
+	Channels []*PluginChannel
+
+	// RequiredPluginIds lists plugin ids that are required to be active.
+	// EnforceRequiredPlugins and EnforceRequiredPluginsAfterSync check it;
+	// SyncPluginsActiveState does not call either, so server startup does
+	// not check this list.
+	RequiredPluginIds []string
+}
+
+// SetDefaults fills in zero-value fields with their defaults, matching the
+// convention used by every other *Settings struct in the config.
+func (s *PluginSettings) SetDefaults() {
+	if s.Channels == nil {
+		s.Channels = []*PluginChannel{}
+	}
+
+	if s.RequiredPluginIds == nil {
+		s.RequiredPluginIds = []string{}
+	}
+}
@@ -0,0 +1,28 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+// PluginChannel is a configured source of installable plugins. A channel is
+// simply an HTTPS endpoint that serves a JSON index of available plugin
+// versions, analogous to a package repository.
+type PluginChannel struct {
+	Name string `json:"name"`
+	Url  string `json:"url"`
+}
+
+// PluginChannelIndexEntry describes a single plugin version offered by a
+// channel. Artifacts are fetched over HTTPS and verified against their
+// SHA-256 digest and detached GPG signature before being installed.
+type PluginChannelIndexEntry struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Url       string `json:"url"`
+	Sha256    string `json:"sha256"`
+	Signature string `json:"signature"`
+}
+
+// PluginChannelIndex is the document served by a plugin channel.
+type PluginChannelIndex struct {
+	Plugins []*PluginChannelIndexEntry `json:"plugins"`
+}
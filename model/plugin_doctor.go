@@ -0,0 +1,20 @@
+// Copyright (c) 2018-present Mattermost, Inc. All Rights Reserved.
+// See License.txt for license information.
+
+package model
+
+const (
+	PluginDoctorSeverityWarning = "warning"
+	PluginDoctorSeverityError   = "error"
+)
+
+// PluginDoctorIssue describes a single inconsistency found by `plugin
+// doctor` between the plugin bundles on disk, their DB rows, the active
+// config, and installed signing keys.
+type PluginDoctorIssue struct {
+	Id          string `json:"id"`
+	Severity    string `json:"severity"`
+	Summary     string `json:"summary"`
+	Remediation string `json:"remediation"`
+	Fixed       bool   `json:"fixed"`
+}